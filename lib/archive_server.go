@@ -0,0 +1,107 @@
+package gitbackup
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cgi"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// archiveServer exposes locally backed up mirrors over HTTP for downstream
+// systems: a plain tar.gz archive of HEAD, the git smart HTTP protocol so
+// they can "git clone" straight from the backup, and health/metrics
+// endpoints for monitoring.
+//
+// It only ever serves the legacy "<backupDirectory>/<target>/<repo>" layout
+// - the one addressable by a "<target>/<repo>" URL - regardless of whether
+// BackupOptions.Structured or KeepN are in use for the underlying backups.
+type archiveServer struct {
+	backupDirectory string
+	metrics         *metrics
+	gitBackend      http.Handler
+}
+
+func newArchiveServer(backupDirectory string, m *metrics) *archiveServer {
+	// cgi.Handler runs Path relative to Dir rather than searching PATH, so
+	// resolve git to an absolute path up front.
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		gitPath = "git"
+	}
+	return &archiveServer{
+		backupDirectory: backupDirectory,
+		metrics:         m,
+		gitBackend: &cgi.Handler{
+			Path: gitPath,
+			Args: []string{"http-backend"},
+			Dir:  backupDirectory,
+			Env: []string{
+				"GIT_PROJECT_ROOT=" + backupDirectory,
+				"GIT_HTTP_EXPORT_ALL=1",
+			},
+		},
+	}
+}
+
+func (s *archiveServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/healthz":
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintln(w, "ok")
+	case r.URL.Path == "/metrics":
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprint(w, s.metrics.render())
+	case strings.HasSuffix(r.URL.Path, ".tar.gz"):
+		s.serveArchive(w, r)
+	default:
+		s.serveSmartHTTP(w, r)
+	}
+}
+
+// serveArchive handles "GET /<target>/<repo>.tar.gz" by streaming
+// "git archive --format=tar.gz HEAD" from the matching local mirror.
+func (s *archiveServer) serveArchive(w http.ResponseWriter, r *http.Request) {
+	target, repo, ok := splitTargetAndRepo(strings.TrimSuffix(r.URL.Path, ".tar.gz"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	repoDir := filepath.Join(s.backupDirectory, target, repo)
+
+	lock := repoLock(repoDir)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	cmd := exec.CommandContext(r.Context(), "git", "archive", "--format=tar.gz", "HEAD")
+	cmd.Dir = repoDir
+	w.Header().Set("Content-Type", "application/gzip")
+	cmd.Stdout = w
+	if err := cmd.Run(); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to archive repository: %s", err), http.StatusInternalServerError)
+	}
+}
+
+// serveSmartHTTP handles "/<target>/<repo>/info/refs?service=git-upload-pack"
+// and "/<target>/<repo>/git-upload-pack" by delegating to "git http-backend",
+// after taking a read lock on the repository being served.
+func (s *archiveServer) serveSmartHTTP(w http.ResponseWriter, r *http.Request) {
+	if target, repo, ok := splitTargetAndRepo(r.URL.Path); ok {
+		lock := repoLock(filepath.Join(s.backupDirectory, target, repo))
+		lock.RLock()
+		defer lock.RUnlock()
+	}
+	s.gitBackend.ServeHTTP(w, r)
+}
+
+// splitTargetAndRepo pulls the "<target>/<repo>" prefix off a URL path such
+// as "/mytarget/myrepo/info/refs", returning ok=false if it doesn't have at
+// least two path segments.
+func splitTargetAndRepo(path string) (target string, repo string, ok bool) {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
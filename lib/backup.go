@@ -2,180 +2,109 @@
 // repositories.
 package gitbackup
 
-import(
-	"encoding/json"
+import (
+	"context"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
 	"os"
-	"os/exec"
-	"path/filepath"
-	"strings"
+	"sync"
+	"time"
 )
 
-type repository struct {
-	name string
-	cloneURL string
-}
-
 // BackupTarget backs up an entity that holds one or more git repositories and
 // has an interface to retrieve that list of repositories.
 // Examples of entities include:
 //   - A GitHub user.
 //   - A BitBucket user.
-//   - A GitHub organization.
-func BackupTarget(target map[string]string, backupDirectory string) error {
-	log.Printf(`Backing up target "%s"`, target["name"])
-
-	// Retrieve a list of all the git repositories available from the target.
-	var repoList []repository
-	var err error
-	switch target["source"] {
-	case "github":
-		repoList, err = getGitHubRepoList(target, backupDirectory)
-	case "bitbucket":
-		repoList, err = getBitBucketRepoList(target, backupDirectory)
-	default:
-		err = fmt.Errorf(`"%s" is not a recognized source type`, target["source"])
-	}
-	if (err != nil) {
-		return err
+//   - A GitLab group.
+//   - A Gitea or Gogs organization.
+//
+// Repositories are backed up concurrently according to options.Concurrency.
+// A failure backing up one repository does not stop the others; the errors
+// for every repository that ultimately failed are aggregated into the
+// returned error.
+//
+// After each repository is successfully backed up locally, it is also
+// pushed to every one of destinations in turn.
+func BackupTarget(target SourceConfig, destinations []DestinationConfig, backupDirectory string, options BackupOptions) error {
+	log.Printf(`Backing up target "%s"`, target.Name)
+
+	if options.Zip && options.KeepN <= 0 {
+		return fmt.Errorf("options.Zip requires options.KeepN to be greater than zero")
 	}
 
-	// Back up each repository found.
-	for _, repo := range repoList {
-		backupRepository(
-			target["name"],
-			repo.name,
-			repo.cloneURL,
-			backupDirectory,
-		)
+	if options.LFS && !lfsAvailable() {
+		log.Printf(`Warning: LFS support was requested for target "%s" but the git-lfs binary was not found on PATH; skipping it`, target.Name)
+		options.LFS = false
 	}
 
-	return nil
-}
-
-// getGitHubRepoList finds all the repositories belonging to a given user or
-// organization on GitHub.
-func getGitHubRepoList(target map[string]string, backupDirectory string) ([]repository, error) {
-	// Create URL to request list of repos.
-	var requestURL string = fmt.Sprintf(
-		"https://api.github.com/%s/%s/repos?access_token=%s&per_page=200",
-		target["type"],
-		target["entity"],
-		target["token"],
-	)
-
-	// Retrieve list of repositories.
-	response, err := http.Get(requestURL)
+	source, err := newSource(target)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to connect with the source to retrieve the list of repositories: %s", err)
+		return err
 	}
-	defer response.Body.Close()
-	contents, err := ioutil.ReadAll(response.Body)
+
+	// Retrieve a list of all the git repositories available from the target.
+	repoList, err := source.ListRepositories(context.Background())
 	if err != nil {
-		return nil, fmt.Errorf("Failed to retrieve the list of repositories: %s", err)
+		return err
 	}
 
-	// Parse JSON response.
-	var dat []map[string]interface{}
-	if err := json.Unmarshal(contents, &dat); err != nil {
-		return nil, fmt.Errorf("Failed to parse JSON: %s", err)
+	concurrency := options.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
 	}
 
-	// Make a list of repositories.
-	repoList := make([]repository, len(dat))
-	for i, repo := range dat {
-		repoName, _ := repo["name"].(string)
-		cloneURL, _ := repo["clone_url"].(string)
-		cloneURL = strings.Replace(
-			cloneURL,
-			"https://",
-			fmt.Sprintf("https://%s:%s@", target["entity"], target["token"]),
-			1,
-		)
-		repoList[i] = repository{name: repoName, cloneURL: cloneURL}
+	jobs := make(chan Repository)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var errs *multiError
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for repo := range jobs {
+				if err := backupRepositoryWithRetry(target, destinations, repo, backupDirectory, options); err != nil {
+					mu.Lock()
+					errs = errs.append(fmt.Errorf("%s: %s", repo.name, err))
+					mu.Unlock()
+				}
+			}
+		}()
 	}
 
-	// No errors.
-	return repoList, nil
-}
-
-// getBitBucketRepoList finds all the repositories belonging to a given user on
-// BitBucket.
-func getBitBucketRepoList(target map[string]string, backupDirectory string) ([]repository, error) {
-	// Create URL to request list of repos.
-	// TODO: support pagination.
-	var requestURL string = fmt.Sprintf(
-		"https://%s:%s@bitbucket.org/api/2.0/repositories/%s?page=1&pagelen=100",
-		target["entity"],
-		target["password"],
-		target["entity"],
-	)
-
-	// Retrieve list of repositories.
-	response, err := http.Get(requestURL)
-	if err != nil {
-		return nil, fmt.Errorf("Failed to connect with the source to retrieve the list of repositories: %s", err)
-	}
-	defer response.Body.Close()
-	contents, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return nil, fmt.Errorf("Failed to retrieve the list of repositories: %s", err)
+	for _, repo := range repoList {
+		jobs <- repo
 	}
+	close(jobs)
+	wg.Wait()
 
-	// Parse JSON response.
-	var metadata map[string]json.RawMessage
-	if err := json.Unmarshal(contents, &metadata); err != nil {
-		return nil, fmt.Errorf("Failed to parse JSON: %s", err)
-	}
-	var data []map[string]json.RawMessage
-	if err := json.Unmarshal(metadata["values"], &data); err != nil {
-		return nil, fmt.Errorf("Failed to parse JSON: %s", err)
+	return errs.orNil()
+}
+
+// backupRepositoryWithRetry backs up a single repository, retrying transient
+// git failures up to options.RetryCount times with exponential backoff.
+func backupRepositoryWithRetry(target SourceConfig, destinations []DestinationConfig, repo Repository, backupDirectory string, options BackupOptions) error {
+	backoff := options.RetryBackoff
+	if backoff <= 0 {
+		backoff = time.Second
 	}
 
-	// Make a list of repositories.
-	repoList := make([]repository, len(data))
-	for i, repo := range data {
-		// Parse the remaining JSON message that pertains to this repository.
-		var repoName string
-		if err := json.Unmarshal(repo["name"], &repoName); err != nil {
-			return nil, fmt.Errorf("Failed to parse JSON: %s", err)
-		}
-		var links map[string]json.RawMessage
-		if err := json.Unmarshal(repo["links"], &links); err != nil {
-			return nil, fmt.Errorf("Failed to parse JSON: %s", err)
-		}
-		var cloneLinks []map[string]string
-		if err := json.Unmarshal(links["clone"], &cloneLinks); err != nil {
-			return nil, fmt.Errorf("Failed to parse JSON: %s", err)
+	var lastErr error
+	for attempt := 0; attempt <= options.RetryCount; attempt++ {
+		if attempt > 0 {
+			emitEvent(options, Event{Target: target.Name, Repo: repo.name, Type: EventRetrying, Err: lastErr})
+			time.Sleep(backoff * time.Duration(uint(1)<<uint(attempt-1)))
 		}
 
-		// Find the https URL to use for cloning.
-		var cloneURL string
-		for _, link := range cloneLinks {
-			if link["name"] == "https" {
-				cloneURL = link["href"]
-			}
-		}
-		if cloneURL == "" {
-			return nil, fmt.Errorf("Could not determine HTTPS cloning URL: %s", cloneLinks)
+		lastErr = backupRepository(target, destinations, repo, backupDirectory, options)
+		if lastErr == nil {
+			return nil
 		}
-
-		// Determine URL for cloning.
-		cloneURL = strings.Replace(
-			cloneURL,
-			fmt.Sprintf("https://%s@", target["entity"]),
-			fmt.Sprintf("https://%s:%s@", target["entity"], target["password"]),
-			1,
-		)
-
-		repoList[i] = repository{name: repoName, cloneURL: cloneURL}
 	}
 
-	// No errors.
-	return repoList, nil
+	emitEvent(options, Event{Target: target.Name, Repo: repo.name, Type: EventFailed, Err: lastErr})
+	return lastErr
 }
 
 // backupRepository takes a remote git repository and backs it up locally.
@@ -183,40 +112,115 @@ func getBitBucketRepoList(target map[string]string, backupDirectory string) ([]r
 // contains the content of a normal .git repository but no working directory,
 // which saves space. You can always get a normal repository from the backup by
 // doing a normal git clone of the backup itself.
-func backupRepository(targetName string, repoName string, cloneURL string, backupDirectory string) {
-	var cloneDirectory string = filepath.Join(backupDirectory, targetName, repoName)
+//
+// repo.cloneURL never carries credentials - any token or password required
+// to access it is injected out-of-band via a GIT_ASKPASS helper (see
+// credentials.go), so it never appears in remote.origin.url, in this
+// process's argv, or in the git subprocess's argv.
+//
+// When options.KeepN is greater than zero, every run clones into a fresh
+// sibling directory suffixed with the current Unix timestamp instead of
+// fetching updates in place, then prunes all but the most recent KeepN
+// snapshots. When options.Zip is also set, each finished snapshot is
+// archived into a .tar.gz and its working tree removed.
+func backupRepository(target SourceConfig, destinations []DestinationConfig, repo Repository, backupDirectory string, options BackupOptions) error {
+	repoName := repo.name
+	cloneURL := repo.cloneURL
+
+	basePath := repositoryPath(target, repo, backupDirectory, options.Structured)
+	snapshotting := options.KeepN > 0
+
+	cloneDirectory := basePath
+	if snapshotting {
+		cloneDirectory = fmt.Sprintf("%s-%d", basePath, time.Now().Unix())
+	}
+
 	fmt.Println(fmt.Sprintf("#> %s", repoName))
 	log.Printf(`Backing up repo "%s"`, repoName)
 
-	if _, err := os.Stat(cloneDirectory); os.IsNotExist(err) {
-		// The repo doesn't exist locally, clone it.
+	credential, err := gitCredentialArgs(target)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+
+	// Hold the repository's lock for the whole clone/fetch so Serve's HTTP
+	// server never streams an archive or smart-HTTP response mid-write. The
+	// lock is keyed on the stable basePath, not cloneDirectory, so that in
+	// the legacy layout it's the same lock archiveServer takes (it only ever
+	// knows about the "<target>/<repo>" path) and so repoLocks doesn't accumulate one entry
+	// per timestamped snapshot when options.KeepN is set.
+	lock := repoLock(basePath)
+	lock.Lock()
+	defer lock.Unlock()
+
+	_, err = os.Stat(cloneDirectory)
+	if snapshotting || os.IsNotExist(err) {
+		// Either this repo doesn't exist locally yet, or we're keeping
+		// timestamped snapshots and always clone fresh.
 		log.Printf("Cloning %s to %s", cloneURL, cloneDirectory)
+		emitEvent(options, Event{Target: target.Name, Repo: repoName, Type: EventCloning})
 
-		cmd := exec.Command("git", "clone", "--mirror", cloneURL, cloneDirectory)
-		cmdOut, err := cmd.CombinedOutput()
+		cmdOut, err := runGit("", credential, "clone", "--mirror", cloneURL, cloneDirectory)
 		if err != nil {
-			fmt.Println("Error cloning the repository:", err)
-		} else {
-			fmt.Println("Cloned repository.")
-			if len(cmdOut) > 0 {
-				fmt.Printf(string(cmdOut))
+			return fmt.Errorf("Error cloning the repository: %s: %s", err, cmdOut)
+		}
+		fmt.Println("Cloned repository.")
+		if len(cmdOut) > 0 {
+			fmt.Printf(string(cmdOut))
+		}
+
+		if options.LFS {
+			if err := lfsFetchAll(cloneDirectory, credential); err != nil {
+				return err
 			}
 		}
 	} else {
-		// The repo already exists, pull updates.
+		// The repo already exists, pull updates. Re-authenticate on every
+		// fetch rather than relying on anything persisted to disk.
 		log.Printf("Pulling git repo in %s", cloneDirectory)
+		emitEvent(options, Event{Target: target.Name, Repo: repoName, Type: EventFetching})
 
-		cmd := exec.Command("git", "fetch", "-p", cloneURL)
-		cmd.Dir = cloneDirectory
-		cmdOut, err := cmd.CombinedOutput()
+		cmdOut, err := runGit(cloneDirectory, credential, "fetch", "-p")
 		if err != nil {
-			fmt.Println("Error pulling in the repository:", err)
-		} else {
-			// Display pulled information.
-			fmt.Println("Pulled latest updates in the repository.")
-			if len(cmdOut) > 0 {
-				fmt.Printf(string(cmdOut))
+			return fmt.Errorf("Error pulling in the repository: %s: %s", err, cmdOut)
+		}
+		// Display pulled information.
+		fmt.Println("Pulled latest updates in the repository.")
+		if len(cmdOut) > 0 {
+			fmt.Printf(string(cmdOut))
+		}
+
+		if options.LFS {
+			if err := lfsFetchAll(cloneDirectory, credential); err != nil {
+				return err
 			}
 		}
 	}
+
+	emitEvent(options, Event{Target: target.Name, Repo: repoName, Type: EventSucceeded, Duration: time.Since(start)})
+
+	for _, destinationConfig := range destinations {
+		destination, err := newDestination(destinationConfig)
+		if err != nil {
+			return err
+		}
+		if err := destination.PushMirror(Repository{name: repoName, cloneURL: cloneURL}, cloneDirectory, options.LFS); err != nil {
+			return err
+		}
+	}
+
+	if options.Zip {
+		if _, err := archiveSnapshot(cloneDirectory); err != nil {
+			return err
+		}
+	}
+
+	if snapshotting {
+		if err := pruneSnapshots(basePath, options.KeepN); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
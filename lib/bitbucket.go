@@ -0,0 +1,115 @@
+package gitbackup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// bitBucketSource lists the repositories belonging to a BitBucket user.
+type bitBucketSource struct {
+	config SourceConfig
+}
+
+func newBitBucketSource(config SourceConfig) *bitBucketSource {
+	return &bitBucketSource{config: config}
+}
+
+// ListRepositories finds all the repositories belonging to the configured
+// BitBucket user, following the "next" link in the response envelope until
+// it is no longer present.
+func (s *bitBucketSource) ListRepositories(ctx context.Context) ([]Repository, error) {
+	target := s.config
+
+	requestURL := fmt.Sprintf(
+		"https://api.bitbucket.org/2.0/repositories/%s?pagelen=100",
+		target.Entity,
+	)
+
+	var repoList []Repository
+	for requestURL != "" {
+		data, nextURL, err := s.fetchPage(ctx, requestURL)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, repo := range data {
+			// Parse the remaining JSON message that pertains to this repository.
+			var repoName string
+			if err := json.Unmarshal(repo["name"], &repoName); err != nil {
+				return nil, fmt.Errorf("Failed to parse JSON: %s", err)
+			}
+			var links map[string]json.RawMessage
+			if err := json.Unmarshal(repo["links"], &links); err != nil {
+				return nil, fmt.Errorf("Failed to parse JSON: %s", err)
+			}
+			var cloneLinks []map[string]string
+			if err := json.Unmarshal(links["clone"], &cloneLinks); err != nil {
+				return nil, fmt.Errorf("Failed to parse JSON: %s", err)
+			}
+
+			// Find the https URL to use for cloning.
+			var cloneURL string
+			for _, link := range cloneLinks {
+				if link["name"] == "https" {
+					cloneURL = link["href"]
+				}
+			}
+			if cloneURL == "" {
+				return nil, fmt.Errorf("Could not determine HTTPS cloning URL: %s", cloneLinks)
+			}
+
+			// No credentials are embedded in the clone URL - authentication
+			// happens out-of-band when the repository is actually cloned/fetched,
+			// see credentials.go.
+			repoList = append(repoList, Repository{
+				name:     repoName,
+				cloneURL: cloneURL,
+				hoster:   "bitbucket",
+				owner:    target.Entity,
+			})
+		}
+
+		requestURL = nextURL
+	}
+
+	return repoList, nil
+}
+
+// fetchPage retrieves a single page of the repository listing and returns
+// the URL of the next page, if any, taken from the response's "next" field.
+func (s *bitBucketSource) fetchPage(ctx context.Context, requestURL string) ([]map[string]json.RawMessage, string, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to build request to retrieve the list of repositories: %s", err)
+	}
+	request.SetBasicAuth(s.config.Entity, s.config.Password)
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to connect with the source to retrieve the list of repositories: %s", err)
+	}
+	defer response.Body.Close()
+	contents, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to retrieve the list of repositories: %s", err)
+	}
+
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(contents, &envelope); err != nil {
+		return nil, "", fmt.Errorf("Failed to parse JSON: %s", err)
+	}
+	var data []map[string]json.RawMessage
+	if err := json.Unmarshal(envelope["values"], &data); err != nil {
+		return nil, "", fmt.Errorf("Failed to parse JSON: %s", err)
+	}
+
+	var nextURL string
+	if raw, ok := envelope["next"]; ok {
+		json.Unmarshal(raw, &nextURL)
+	}
+
+	return data, nextURL, nil
+}
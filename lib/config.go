@@ -0,0 +1,104 @@
+package gitbackup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config is the top-level YAML configuration for gitbackup. It lists every
+// source to pull repositories from and every destination to mirror them to.
+type Config struct {
+	BackupDirectory string              `yaml:"backup_directory"`
+	Sources         []SourceConfig      `yaml:"sources"`
+	Destinations    []DestinationConfig `yaml:"destinations"`
+	// PollInterval, used by Serve, is a duration string such as "1h" or
+	// "30m". Defaults to one hour when empty.
+	PollInterval string `yaml:"poll_interval"`
+	// ListenAddress, used by Serve, is the address its HTTP archive server
+	// listens on, e.g. ":8080". Defaults to ":8080" when empty.
+	ListenAddress string `yaml:"listen_address"`
+}
+
+// pollInterval parses PollInterval, defaulting to one hour when it is unset.
+func (c *Config) pollInterval() (time.Duration, error) {
+	if c.PollInterval == "" {
+		return time.Hour, nil
+	}
+	interval, err := time.ParseDuration(c.PollInterval)
+	if err != nil {
+		return 0, fmt.Errorf(`Failed to parse "poll_interval": %s`, err)
+	}
+	return interval, nil
+}
+
+// listenAddress returns ListenAddress, defaulting to ":8080" when it is
+// unset.
+func (c *Config) listenAddress() string {
+	if c.ListenAddress == "" {
+		return ":8080"
+	}
+	return c.ListenAddress
+}
+
+// SourceConfig describes one entity to back up, e.g. a GitHub user or a
+// GitLab group.
+type SourceConfig struct {
+	Name         string   `yaml:"name"`
+	Provider     string   `yaml:"provider"` // github, bitbucket, gitlab, gitea, gogs
+	Type         string   `yaml:"type"`     // user, org, group, depending on provider
+	Entity       string   `yaml:"entity"`   // username, org name, or group name/id
+	Token        string   `yaml:"token"`
+	Password     string   `yaml:"password"`     // used by bitbucket app passwords
+	BaseURL      string   `yaml:"base_url"`     // override for self-hosted instances
+	Destinations []string `yaml:"destinations"` // names of Config.Destinations to mirror to
+}
+
+// DestinationConfig describes a place repositories can be mirrored to in
+// addition to being backed up locally.
+type DestinationConfig struct {
+	Name     string `yaml:"name"`
+	Provider string `yaml:"provider"` // gitea, gitlab, bitbucket, git
+	Entity   string `yaml:"entity"`   // workspace/owner the repository is created under, if required
+	BaseURL  string `yaml:"base_url"`
+	Token    string `yaml:"token"`
+}
+
+// DestinationsFor resolves the destination names listed on source into their
+// full DestinationConfig, skipping any name that doesn't match an entry in
+// c.Destinations.
+func (c *Config) DestinationsFor(source SourceConfig) []DestinationConfig {
+	if len(source.Destinations) == 0 {
+		return nil
+	}
+
+	byName := make(map[string]DestinationConfig, len(c.Destinations))
+	for _, destination := range c.Destinations {
+		byName[destination.Name] = destination
+	}
+
+	var resolved []DestinationConfig
+	for _, name := range source.Destinations {
+		if destination, ok := byName[name]; ok {
+			resolved = append(resolved, destination)
+		}
+	}
+	return resolved
+}
+
+// LoadConfig reads and parses a YAML configuration file from disk.
+func LoadConfig(path string) (*Config, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read config file: %s", err)
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(contents, &config); err != nil {
+		return nil, fmt.Errorf("Failed to parse config file: %s", err)
+	}
+
+	return &config, nil
+}
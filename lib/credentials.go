@@ -0,0 +1,127 @@
+package gitbackup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+)
+
+// askPassScript is installed as GIT_ASKPASS so that a credential's
+// username/password travel to git through environment variables rather
+// than ever appearing as a git command-line argument, where they'd be
+// visible in `ps` or /proc/<pid>/cmdline.
+const askPassScript = `#!/bin/sh
+case "$1" in
+	Username*) printf '%s' "$GITBACKUP_ASKPASS_USERNAME" ;;
+	*) printf '%s' "$GITBACKUP_ASKPASS_PASSWORD" ;;
+esac
+`
+
+var (
+	askPassPathOnce sync.Once
+	askPassPath     string
+	askPassErr      error
+)
+
+// resolveAskPass writes the askpass helper script to a private temp file the
+// first time it's needed and returns its path on every call thereafter.
+func resolveAskPass() (string, error) {
+	askPassPathOnce.Do(func() {
+		dir, err := ioutil.TempDir("", "gitbackup-askpass")
+		if err != nil {
+			askPassErr = fmt.Errorf("Failed to create askpass helper directory: %s", err)
+			return
+		}
+		path := filepath.Join(dir, "askpass.sh")
+		if err := ioutil.WriteFile(path, []byte(askPassScript), 0700); err != nil {
+			askPassErr = fmt.Errorf("Failed to write askpass helper: %s", err)
+			return
+		}
+		askPassPath = path
+	})
+	return askPassPath, askPassErr
+}
+
+// gitCredential carries everything needed to authenticate a git invocation:
+// the "-c" arguments that wire the askpass helper in, and the environment
+// variables it reads the actual username/password from. Neither the
+// username nor the password ever appears in a git argument.
+type gitCredential struct {
+	args []string
+	env  []string
+}
+
+// gitCredentialFor builds the gitCredential that authenticates as
+// username/password, or the zero value when ok is false.
+func gitCredentialFor(username string, password string, ok bool) (gitCredential, error) {
+	if !ok {
+		return gitCredential{}, nil
+	}
+
+	askPass, err := resolveAskPass()
+	if err != nil {
+		return gitCredential{}, err
+	}
+
+	return gitCredential{
+		args: []string{"-c", "core.askpass=" + askPass},
+		env: []string{
+			"GIT_ASKPASS=" + askPass,
+			"GITBACKUP_ASKPASS_USERNAME=" + username,
+			"GITBACKUP_ASKPASS_PASSWORD=" + password,
+		},
+	}, nil
+}
+
+// credentialBasicAuth returns the HTTP basic-auth username/password that
+// authenticates requests made to the given target. ok is false when the
+// target carries no credential.
+func credentialBasicAuth(target SourceConfig) (username string, password string, ok bool) {
+	switch target.Provider {
+	case "github":
+		if target.Token == "" {
+			return "", "", false
+		}
+		return "x-access-token", target.Token, true
+	case "gitlab", "gitea", "gogs":
+		if target.Token == "" {
+			return "", "", false
+		}
+		return "oauth2", target.Token, true
+	case "bitbucket":
+		if target.Password == "" {
+			return "", "", false
+		}
+		return target.Entity, target.Password, true
+	default:
+		return "", "", false
+	}
+}
+
+// gitCredentialArgs returns the gitCredential that authenticates git
+// requests made to target, or the zero value if target has no credential to
+// inject.
+func gitCredentialArgs(target SourceConfig) (gitCredential, error) {
+	username, password, ok := credentialBasicAuth(target)
+	return gitCredentialFor(username, password, ok)
+}
+
+// runGit runs "git" with args prefixed by credential's "-c" arguments and,
+// when credential carries one, its environment variables layered on top of
+// this process's environment - so the credential is available to the
+// askpass helper without ever being passed as a git argument. dir, when
+// non-empty, becomes the subprocess's working directory.
+func runGit(dir string, credential gitCredential, args ...string) ([]byte, error) {
+	fullArgs := append(append([]string{}, credential.args...), args...)
+	cmd := exec.Command("git", fullArgs...)
+	if dir != "" {
+		cmd.Dir = dir
+	}
+	if len(credential.env) > 0 {
+		cmd.Env = append(os.Environ(), credential.env...)
+	}
+	return cmd.CombinedOutput()
+}
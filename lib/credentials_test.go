@@ -0,0 +1,134 @@
+package gitbackup
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGitCredentialArgsNeverAppearInArgv proves that, for every source
+// provider, none of the arguments gitCredentialArgs adds to a git
+// invocation - nor the arguments it's combined with to build a real
+// clone/fetch command - ever contain the token or password. The credential
+// only ever reaches git through gitCredential.env (see runGit), never argv.
+func TestGitCredentialArgsNeverAppearInArgv(t *testing.T) {
+	const secret = "super-secret-token"
+
+	targets := []SourceConfig{
+		{Provider: "github", Token: secret},
+		{Provider: "gitlab", Token: secret},
+		{Provider: "gitea", Token: secret},
+		{Provider: "gogs", Token: secret},
+		{Provider: "bitbucket", Entity: "someuser", Password: secret},
+	}
+
+	for _, target := range targets {
+		credential, err := gitCredentialArgs(target)
+		if err != nil {
+			t.Fatalf("%s: Failed to build credential: %s", target.Provider, err)
+		}
+
+		fullArgs := append(append([]string{}, credential.args...), "clone", "--mirror", "https://example.invalid/repo.git", "mirror")
+		for _, arg := range fullArgs {
+			if strings.Contains(arg, secret) {
+				t.Fatalf("%s: secret appeared in git argv: %v", target.Provider, fullArgs)
+			}
+		}
+
+		// The secret must instead be reachable only through the
+		// environment the askpass helper reads from.
+		found := false
+		for _, kv := range credential.env {
+			if strings.Contains(kv, secret) {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("%s: secret did not appear in gitCredential.env", target.Provider)
+		}
+	}
+}
+
+// TestAskPassHelperReadsFromEnv proves the GIT_ASKPASS helper installed by
+// gitCredentialFor answers git's username/password prompts from environment
+// variables rather than from any argument - which is what keeps the secret
+// out of argv while still authenticating the request.
+func TestAskPassHelperReadsFromEnv(t *testing.T) {
+	credential, err := gitCredentialFor("some-user", "some-password", true)
+	if err != nil {
+		t.Fatalf("Failed to build credential: %s", err)
+	}
+
+	askPass, err := resolveAskPass()
+	if err != nil {
+		t.Fatalf("Failed to resolve askpass helper: %s", err)
+	}
+
+	for _, tc := range []struct {
+		prompt string
+		want   string
+	}{
+		{prompt: "Username for 'https://example.invalid':", want: "some-user"},
+		{prompt: "Password for 'https://example.invalid':", want: "some-password"},
+	} {
+		cmd := exec.Command(askPass, tc.prompt)
+		cmd.Env = append(os.Environ(), credential.env...)
+		var out bytes.Buffer
+		cmd.Stdout = &out
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("askpass helper failed: %s", err)
+		}
+		if out.String() != tc.want {
+			t.Fatalf("askpass helper for %q = %q, want %q", tc.prompt, out.String(), tc.want)
+		}
+	}
+}
+
+// TestCloneDoesNotLeakCredentials exercises the same invocation
+// backupRepository uses to clone a repository - a clean cloneURL plus
+// gitCredentialArgs - against a local repository, and proves the token
+// never appears in the resulting mirror's on-disk .git/config.
+func TestCloneDoesNotLeakCredentials(t *testing.T) {
+	const token = "super-secret-token"
+
+	tmp, err := ioutil.TempDir("", "gitbackup-credentials-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %s", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	originPath := filepath.Join(tmp, "origin.git")
+	if _, err := runGit(tmp, gitCredential{}, "init", "--bare", originPath); err != nil {
+		t.Fatalf("Failed to create origin repository: %s", err)
+	}
+
+	// cloneURL is what repo.cloneURL would be - it never carries a
+	// credential, regardless of provider or token.
+	cloneURL := "file://" + originPath
+	cloneDirectory := filepath.Join(tmp, "mirror")
+
+	target := SourceConfig{Provider: "github", Token: token}
+	credential, err := gitCredentialArgs(target)
+	if err != nil {
+		t.Fatalf("Failed to build credential: %s", err)
+	}
+
+	if out, err := runGit("", credential, "clone", "--mirror", cloneURL, cloneDirectory); err != nil {
+		t.Fatalf("Failed to clone: %s: %s", err, out)
+	}
+
+	config, err := ioutil.ReadFile(filepath.Join(cloneDirectory, "config"))
+	if err != nil {
+		t.Fatalf("Failed to read the clone's config: %s", err)
+	}
+	if strings.Contains(string(config), token) {
+		t.Fatalf("token appeared in .git/config:\n%s", config)
+	}
+	if !strings.Contains(string(config), cloneURL) {
+		t.Fatalf("remote.origin.url was not the clean cloneURL:\n%s", config)
+	}
+}
@@ -0,0 +1,67 @@
+package gitbackup
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// Serve runs BackupTarget for every configured source on a loop paced by
+// config's poll interval, jittered by up to 10% so multiple gitbackup
+// instances don't all hit their sources at once, and exposes the resulting
+// mirrors over HTTP (see archiveServer) until ctx is canceled.
+func Serve(ctx context.Context, config *Config, options BackupOptions) error {
+	interval, err := config.pollInterval()
+	if err != nil {
+		return err
+	}
+
+	stats := newMetrics()
+	if options.Events == nil {
+		options.Events = make(chan Event, 16)
+	}
+	go func() {
+		for event := range options.Events {
+			stats.observe(event)
+		}
+	}()
+
+	server := &http.Server{
+		Addr:    config.listenAddress(),
+		Handler: newArchiveServer(config.BackupDirectory, stats),
+	}
+	serverErrors := make(chan error, 1)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			serverErrors <- err
+		}
+	}()
+
+	for {
+		for _, source := range config.Sources {
+			if err := BackupTarget(source, config.DestinationsFor(source), config.BackupDirectory, options); err != nil {
+				log.Printf(`Error backing up target "%s": %s`, source.Name, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			server.Close()
+			return ctx.Err()
+		case err := <-serverErrors:
+			return err
+		case <-time.After(jitter(interval)):
+		}
+	}
+}
+
+// jitter adds up to 10% of extra delay on top of d, so backups triggered by
+// multiple instances spread out instead of syncing up over time.
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return d
+	}
+	return d + time.Duration(rand.Int63n(int64(d)/10+1))
+}
@@ -0,0 +1,70 @@
+package gitbackup
+
+import (
+	"fmt"
+)
+
+// Destination is the extension point for anywhere a backed-up repository can
+// be mirrored to in addition to being stored locally. Adding a new provider
+// means implementing this interface and registering it in newDestination
+// below.
+type Destination interface {
+	// PushMirror makes sure repo exists on the destination, creating it if
+	// necessary, then pushes every ref from the local mirror at localDir to
+	// it. When lfs is true, it also pushes every LFS object referenced by
+	// that mirror.
+	PushMirror(repo Repository, localDir string, lfs bool) error
+}
+
+// newDestination builds the Destination implementation for a
+// DestinationConfig's provider.
+func newDestination(destination DestinationConfig) (Destination, error) {
+	switch destination.Provider {
+	case "gitea", "gogs":
+		return newGiteaDestination(destination), nil
+	case "gitlab":
+		return newGitLabDestination(destination), nil
+	case "bitbucket":
+		return newBitBucketDestination(destination), nil
+	case "git":
+		return newGitDestination(destination), nil
+	default:
+		return nil, fmt.Errorf(`"%s" is not a recognized destination provider`, destination.Provider)
+	}
+}
+
+// destinationCredentialArgs returns the gitCredential that authenticates a
+// push to destination, following the same out-of-band approach
+// backupRepository uses for sources (see credentials.go).
+func destinationCredentialArgs(destination DestinationConfig) (gitCredential, error) {
+	if destination.Token == "" {
+		return gitCredential{}, nil
+	}
+
+	var username string
+	switch destination.Provider {
+	case "bitbucket":
+		username = "x-token-auth"
+	default: // gitlab, gitea, gogs, git
+		username = "oauth2"
+	}
+	return gitCredentialFor(username, destination.Token, true)
+}
+
+// pushMirror runs "git push --mirror destURL" from localDir, authenticating
+// via credential rather than embedding anything in destURL. When lfs is
+// true, it follows up with a "git lfs push --all".
+func pushMirror(localDir string, destURL string, credential gitCredential, lfs bool) error {
+	out, err := runGit(localDir, credential, "push", "--mirror", destURL)
+	if err != nil {
+		return fmt.Errorf("Error pushing mirror to %s: %s: %s", destURL, err, out)
+	}
+
+	if lfs {
+		if err := lfsPushAll(localDir, destURL, credential); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
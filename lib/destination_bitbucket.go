@@ -0,0 +1,75 @@
+package gitbackup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// bitBucketDestination mirrors repositories into a BitBucket workspace.
+type bitBucketDestination struct {
+	config DestinationConfig
+}
+
+func newBitBucketDestination(config DestinationConfig) *bitBucketDestination {
+	return &bitBucketDestination{config: config}
+}
+
+// PushMirror creates repo in the destination workspace if it doesn't already
+// exist, then pushes every ref to it.
+func (d *bitBucketDestination) PushMirror(repo Repository, localDir string, lfs bool) error {
+	destURL, err := d.ensureRepository(repo)
+	if err != nil {
+		return err
+	}
+	credential, err := destinationCredentialArgs(d.config)
+	if err != nil {
+		return err
+	}
+	return pushMirror(localDir, destURL, credential, lfs)
+}
+
+func (d *bitBucketDestination) ensureRepository(repo Repository) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"scm": "git",
+	})
+	if err != nil {
+		return "", fmt.Errorf("Failed to build repository creation request: %s", err)
+	}
+
+	requestURL := fmt.Sprintf(
+		"https://api.bitbucket.org/2.0/repositories/%s/%s",
+		d.config.Entity,
+		repo.name,
+	)
+	request, err := http.NewRequestWithContext(context.Background(), "POST", requestURL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("Failed to build repository creation request: %s", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", d.config.Token))
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("Failed to create repository on destination: %s", err)
+	}
+	defer response.Body.Close()
+	respBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read repository creation response: %s", err)
+	}
+
+	// A 400 saying the repository already exists is fine. Any other 400
+	// (invalid name, over quota, bad payload) is a genuine failure and must
+	// not be swallowed - pushing to a repository that was never created
+	// would otherwise fail with a much more confusing error.
+	alreadyExists := response.StatusCode == http.StatusBadRequest && bytes.Contains(respBody, []byte("already exists"))
+	if response.StatusCode >= 300 && !alreadyExists {
+		return "", fmt.Errorf("Failed to create repository on destination: unexpected status %s: %s", response.Status, respBody)
+	}
+
+	return fmt.Sprintf("https://bitbucket.org/%s/%s.git", d.config.Entity, repo.name), nil
+}
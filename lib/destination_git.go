@@ -0,0 +1,26 @@
+package gitbackup
+
+import "fmt"
+
+// gitDestination mirrors repositories to a plain SSH or HTTPS git remote.
+// It does no repository creation - the remote is expected to already exist,
+// or to auto-vivify repositories on push (as e.g. some git hosting setups
+// do).
+type gitDestination struct {
+	config DestinationConfig
+}
+
+func newGitDestination(config DestinationConfig) *gitDestination {
+	return &gitDestination{config: config}
+}
+
+// PushMirror pushes every ref from the local mirror to
+// "<base_url>/<repo>.git".
+func (d *gitDestination) PushMirror(repo Repository, localDir string, lfs bool) error {
+	destURL := fmt.Sprintf("%s/%s.git", d.config.BaseURL, repo.name)
+	credential, err := destinationCredentialArgs(d.config)
+	if err != nil {
+		return err
+	}
+	return pushMirror(localDir, destURL, credential, lfs)
+}
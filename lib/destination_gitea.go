@@ -0,0 +1,74 @@
+package gitbackup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// giteaDestination mirrors repositories into a Gitea (or Gogs, via
+// newGogsSource-style compatibility) instance.
+type giteaDestination struct {
+	config DestinationConfig
+}
+
+func newGiteaDestination(config DestinationConfig) *giteaDestination {
+	return &giteaDestination{config: config}
+}
+
+// PushMirror migrates repo into the destination if it doesn't already exist,
+// then pushes every ref to it.
+func (d *giteaDestination) PushMirror(repo Repository, localDir string, lfs bool) error {
+	destURL, err := d.ensureRepository(repo)
+	if err != nil {
+		return err
+	}
+	credential, err := destinationCredentialArgs(d.config)
+	if err != nil {
+		return err
+	}
+	return pushMirror(localDir, destURL, credential, lfs)
+}
+
+// ensureRepository creates repo on the destination via the migrate API if it
+// is missing, and returns the clean URL to push to. Migrate has Gitea clone
+// repo.cloneURL itself, which carries no credential, so it can't pull a
+// private source on its own - that's fine, since the subsequent
+// "git push --mirror" populates the destination regardless of whether the
+// migrate step managed to fetch anything.
+func (d *giteaDestination) ensureRepository(repo Repository) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"clone_addr": repo.cloneURL,
+		"repo_name":  repo.name,
+		"repo_owner": d.config.Entity,
+		"mirror":     false,
+	})
+	if err != nil {
+		return "", fmt.Errorf("Failed to build migrate request: %s", err)
+	}
+
+	requestURL := fmt.Sprintf("%s/api/v1/repos/migrate", d.config.BaseURL)
+	request, err := http.NewRequestWithContext(context.Background(), "POST", requestURL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("Failed to build migrate request: %s", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if d.config.Token != "" {
+		request.Header.Set("Authorization", fmt.Sprintf("token %s", d.config.Token))
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("Failed to create repository on destination: %s", err)
+	}
+	defer response.Body.Close()
+
+	// A Conflict means the repository already exists, which is fine.
+	if response.StatusCode >= 300 && response.StatusCode != http.StatusConflict {
+		return "", fmt.Errorf("Failed to create repository on destination: unexpected status %s", response.Status)
+	}
+
+	return fmt.Sprintf("%s/%s/%s.git", d.config.BaseURL, d.config.Entity, repo.name), nil
+}
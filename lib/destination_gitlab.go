@@ -0,0 +1,118 @@
+package gitbackup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+// gitLabDestination mirrors repositories into a GitLab instance.
+type gitLabDestination struct {
+	config DestinationConfig
+}
+
+func newGitLabDestination(config DestinationConfig) *gitLabDestination {
+	return &gitLabDestination{config: config}
+}
+
+// PushMirror creates repo as a project on the destination if it doesn't
+// already exist, then pushes every ref to it.
+func (d *gitLabDestination) PushMirror(repo Repository, localDir string, lfs bool) error {
+	destURL, err := d.ensureRepository(repo)
+	if err != nil {
+		return err
+	}
+	credential, err := destinationCredentialArgs(d.config)
+	if err != nil {
+		return err
+	}
+	return pushMirror(localDir, destURL, credential, lfs)
+}
+
+func (d *gitLabDestination) ensureRepository(repo Repository) (string, error) {
+	baseURL := d.config.BaseURL
+	if baseURL == "" {
+		baseURL = gitLabBaseURL
+	}
+
+	namespaceID, err := d.resolveNamespaceID(baseURL)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"name":         repo.name,
+		"namespace_id": namespaceID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("Failed to build project creation request: %s", err)
+	}
+
+	requestURL := fmt.Sprintf("%s/api/v4/projects", baseURL)
+	request, err := http.NewRequestWithContext(context.Background(), "POST", requestURL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("Failed to build project creation request: %s", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("PRIVATE-TOKEN", d.config.Token)
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("Failed to create repository on destination: %s", err)
+	}
+	defer response.Body.Close()
+	respBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read repository creation response: %s", err)
+	}
+
+	// A 400 whose message says the name has already been taken is GitLab's
+	// way of saying the project already exists, which is fine. Any other
+	// 400 (invalid name, over quota, ...) is a genuine failure and must not
+	// be swallowed - pushing to a project that was never created would
+	// otherwise fail with a much more confusing error.
+	alreadyExists := response.StatusCode == http.StatusBadRequest && bytes.Contains(respBody, []byte("has already been taken"))
+	if response.StatusCode >= 300 && !alreadyExists {
+		return "", fmt.Errorf("Failed to create repository on destination: unexpected status %s: %s", response.Status, respBody)
+	}
+
+	return fmt.Sprintf("%s/%s/%s.git", baseURL, d.config.Entity, repo.name), nil
+}
+
+// resolveNamespaceID looks up the ID of the namespace (user or
+// group) repositories are pushed under, so project creation and the push
+// URL agree on where the project lives.
+func (d *gitLabDestination) resolveNamespaceID(baseURL string) (int, error) {
+	requestURL := fmt.Sprintf("%s/api/v4/namespaces/%s", baseURL, url.PathEscape(d.config.Entity))
+	request, err := http.NewRequestWithContext(context.Background(), "GET", requestURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to build namespace lookup request: %s", err)
+	}
+	request.Header.Set("PRIVATE-TOKEN", d.config.Token)
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to look up destination namespace: %s", err)
+	}
+	defer response.Body.Close()
+	respBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to read namespace lookup response: %s", err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("Failed to look up destination namespace %q: unexpected status %s: %s", d.config.Entity, response.Status, respBody)
+	}
+
+	var namespace struct {
+		ID int `json:"id"`
+	}
+	if err := json.Unmarshal(respBody, &namespace); err != nil {
+		return 0, fmt.Errorf("Failed to parse namespace lookup response: %s", err)
+	}
+
+	return namespace.ID, nil
+}
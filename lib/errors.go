@@ -0,0 +1,38 @@
+package gitbackup
+
+import (
+	"fmt"
+	"strings"
+)
+
+// multiError aggregates the errors encountered while backing up multiple
+// repositories concurrently. A nil *multiError is valid and behaves like no
+// error at all.
+type multiError struct {
+	errors []error
+}
+
+// append records err on a (possibly nil) multiError and returns the result.
+func (e *multiError) append(err error) *multiError {
+	if e == nil {
+		e = &multiError{}
+	}
+	e.errors = append(e.errors, err)
+	return e
+}
+
+// orNil returns e as an error, or nil if it never collected a failure.
+func (e *multiError) orNil() error {
+	if e == nil || len(e.errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *multiError) Error() string {
+	messages := make([]string, len(e.errors))
+	for i, err := range e.errors {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("%d repositories failed to back up:\n%s", len(e.errors), strings.Join(messages, "\n"))
+}
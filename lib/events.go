@@ -0,0 +1,40 @@
+package gitbackup
+
+import "time"
+
+// EventType identifies what a progress Event describes.
+type EventType int
+
+const (
+	// EventCloning fires when a repository starts its first, full clone.
+	EventCloning EventType = iota
+	// EventFetching fires when an existing mirror starts fetching updates.
+	EventFetching
+	// EventRetrying fires after a failed attempt, before the next one.
+	EventRetrying
+	// EventSucceeded fires once a repository's clone or fetch completes.
+	EventSucceeded
+	// EventFailed fires once a repository has exhausted all of its retries.
+	EventFailed
+)
+
+// Event reports the progress of a single repository's backup, so a caller
+// can render progress (e.g. a CLI or daemon) without parsing log output.
+type Event struct {
+	Target string
+	Repo   string
+	Type   EventType
+	Err    error
+	// Duration is set on EventSucceeded and reports how long the clone or
+	// fetch took, so a caller can track it (e.g. as a metric).
+	Duration time.Duration
+}
+
+// emitEvent sends event on options.Events if the caller asked for progress
+// events, and is a no-op otherwise.
+func emitEvent(options BackupOptions, event Event) {
+	if options.Events == nil {
+		return
+	}
+	options.Events <- event
+}
@@ -0,0 +1,104 @@
+package gitbackup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// giteaPageSize is how many repositories are requested per page. Gitea (and
+// Gogs) default to roughly 30 when "limit" is omitted, which would silently
+// truncate any entity with more repositories than that.
+const giteaPageSize = 50
+
+// giteaSource lists the repositories belonging to a Gitea user or
+// organization. Gogs speaks a compatible API, so gogs.go reuses this
+// implementation against a Gogs instance's base URL.
+type giteaSource struct {
+	config SourceConfig
+}
+
+func newGiteaSource(config SourceConfig) *giteaSource {
+	return &giteaSource{config: config}
+}
+
+// ListRepositories finds all the repositories belonging to the configured
+// Gitea user or organization, following pagination until a page comes back
+// shorter than giteaPageSize.
+func (s *giteaSource) ListRepositories(ctx context.Context) ([]Repository, error) {
+	target := s.config
+
+	if target.BaseURL == "" {
+		return nil, fmt.Errorf(`"base_url" is required for the "%s" source provider`, target.Provider)
+	}
+
+	var resource string
+	switch target.Type {
+	case "org":
+		resource = fmt.Sprintf("%s/api/v1/orgs/%s/repos", target.BaseURL, target.Entity)
+	default:
+		resource = fmt.Sprintf("%s/api/v1/users/%s/repos", target.BaseURL, target.Entity)
+	}
+
+	var repoList []Repository
+	for page := 1; ; page++ {
+		requestURL := fmt.Sprintf("%s?page=%d&limit=%d", resource, page, giteaPageSize)
+		dat, err := s.fetchPage(ctx, requestURL)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, repo := range dat {
+			repoName, _ := repo["name"].(string)
+			cloneURL, _ := repo["clone_url"].(string)
+			owner := target.Entity
+			if ownerInfo, ok := repo["owner"].(map[string]interface{}); ok {
+				if login, ok := ownerInfo["login"].(string); ok {
+					owner = login
+				}
+			}
+			repoList = append(repoList, Repository{
+				name:     repoName,
+				cloneURL: cloneURL,
+				hoster:   target.Provider,
+				owner:    owner,
+			})
+		}
+
+		if len(dat) < giteaPageSize {
+			break
+		}
+	}
+
+	return repoList, nil
+}
+
+// fetchPage retrieves a single page of the repository listing.
+func (s *giteaSource) fetchPage(ctx context.Context, requestURL string) ([]map[string]interface{}, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to build request to retrieve the list of repositories: %s", err)
+	}
+	if s.config.Token != "" {
+		request.Header.Set("Authorization", fmt.Sprintf("token %s", s.config.Token))
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect with the source to retrieve the list of repositories: %s", err)
+	}
+	defer response.Body.Close()
+	contents, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to retrieve the list of repositories: %s", err)
+	}
+
+	var dat []map[string]interface{}
+	if err := json.Unmarshal(contents, &dat); err != nil {
+		return nil, fmt.Errorf("Failed to parse JSON: %s", err)
+	}
+
+	return dat, nil
+}
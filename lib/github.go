@@ -0,0 +1,172 @@
+package gitbackup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// gitHubLinkNextPattern extracts the "next" URL from a GitHub RFC 5988 Link
+// header, e.g. `<https://api.github.com/...&page=2>; rel="next"`.
+var gitHubLinkNextPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// maxRateLimitRetries bounds how many times fetchPage retries a page after
+// a 403 with no quota remaining, so a reset that never arrives (or an
+// endpoint that returns 403 for an unrelated reason) can't spin forever.
+const maxRateLimitRetries = 5
+
+// defaultRateLimitWait is how long waitForRateLimitReset sleeps when
+// X-RateLimit-Reset is missing or already in the past, so a busy loop can't
+// form by immediately retrying the identical request.
+const defaultRateLimitWait = time.Minute
+
+// gitHubSource lists the repositories belonging to a GitHub user or
+// organization.
+type gitHubSource struct {
+	config SourceConfig
+}
+
+func newGitHubSource(config SourceConfig) *gitHubSource {
+	return &gitHubSource{config: config}
+}
+
+// ListRepositories finds all the repositories belonging to the configured
+// GitHub user or organization, following pagination until every page has
+// been retrieved.
+func (s *gitHubSource) ListRepositories(ctx context.Context) ([]Repository, error) {
+	target := s.config
+
+	var resource string
+	switch target.Type {
+	case "org", "orgs":
+		resource = "orgs"
+	default:
+		resource = "users"
+	}
+	requestURL := fmt.Sprintf(
+		"https://api.github.com/%s/%s/repos?per_page=100",
+		resource,
+		target.Entity,
+	)
+
+	var repoList []Repository
+	for requestURL != "" {
+		dat, nextURL, err := s.fetchPage(ctx, requestURL)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, repo := range dat {
+			repoName, _ := repo["name"].(string)
+			cloneURL, _ := repo["clone_url"].(string)
+			owner := target.Entity
+			if ownerInfo, ok := repo["owner"].(map[string]interface{}); ok {
+				if login, ok := ownerInfo["login"].(string); ok {
+					owner = login
+				}
+			}
+			repoList = append(repoList, Repository{
+				name:     repoName,
+				cloneURL: cloneURL,
+				hoster:   "github",
+				owner:    owner,
+			})
+		}
+
+		requestURL = nextURL
+	}
+
+	return repoList, nil
+}
+
+// fetchPage retrieves a single page of the repository listing, honoring
+// GitHub's rate limit headers and returning the URL of the next page, if
+// any, parsed from the Link header. A 403 with no quota remaining is
+// retried against the same page, up to maxRateLimitRetries times, once the
+// quota resets, rather than being surfaced as a parse failure.
+func (s *gitHubSource) fetchPage(ctx context.Context, requestURL string) ([]map[string]interface{}, string, error) {
+	for attempt := 0; ; attempt++ {
+		request, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+		if err != nil {
+			return nil, "", fmt.Errorf("Failed to build request to retrieve the list of repositories: %s", err)
+		}
+		if s.config.Token != "" {
+			request.Header.Set("Authorization", fmt.Sprintf("token %s", s.config.Token))
+		}
+
+		response, err := httpClient.Do(request)
+		if err != nil {
+			return nil, "", fmt.Errorf("Failed to connect with the source to retrieve the list of repositories: %s", err)
+		}
+		contents, err := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			return nil, "", fmt.Errorf("Failed to retrieve the list of repositories: %s", err)
+		}
+
+		if response.StatusCode == http.StatusForbidden {
+			if remaining, ok := parseRateLimitRemaining(response.Header); ok && remaining == 0 {
+				if attempt >= maxRateLimitRetries {
+					return nil, "", fmt.Errorf("Failed to retrieve the list of repositories: still rate limited after %d retries", attempt)
+				}
+				waitForRateLimitReset(response.Header)
+				continue
+			}
+		}
+		if response.StatusCode != http.StatusOK {
+			return nil, "", fmt.Errorf("Failed to retrieve the list of repositories: unexpected status %s: %s", response.Status, contents)
+		}
+
+		var dat []map[string]interface{}
+		if err := json.Unmarshal(contents, &dat); err != nil {
+			return nil, "", fmt.Errorf("Failed to parse JSON: %s", err)
+		}
+
+		return dat, nextPageURL(response.Header), nil
+	}
+}
+
+// nextPageURL parses the "next" link out of a GitHub Link header, returning
+// "" once there are no more pages.
+func nextPageURL(header http.Header) string {
+	matches := gitHubLinkNextPattern.FindStringSubmatch(header.Get("Link"))
+	if len(matches) < 2 {
+		return ""
+	}
+	return matches[1]
+}
+
+// parseRateLimitRemaining reads GitHub's X-RateLimit-Remaining header.
+func parseRateLimitRemaining(header http.Header) (int, bool) {
+	value := header.Get("X-RateLimit-Remaining")
+	if value == "" {
+		return 0, false
+	}
+	remaining, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false
+	}
+	return remaining, true
+}
+
+// waitForRateLimitReset sleeps until the Unix timestamp in
+// X-RateLimit-Reset, so the next request is made once the quota refills. If
+// the header is missing or already in the past, it falls back to sleeping
+// defaultRateLimitWait instead of returning immediately, which would turn
+// fetchPage's retry loop into a busy spin against an unchanged quota.
+func waitForRateLimitReset(header http.Header) {
+	wait := defaultRateLimitWait
+	if value := header.Get("X-RateLimit-Reset"); value != "" {
+		if resetUnix, err := strconv.ParseInt(value, 10, 64); err == nil {
+			if untilReset := time.Until(time.Unix(resetUnix, 0)); untilReset > 0 {
+				wait = untilReset
+			}
+		}
+	}
+	time.Sleep(wait)
+}
@@ -0,0 +1,109 @@
+package gitbackup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+)
+
+// gitLabBaseURL is used when a SourceConfig does not override it, pointing
+// at the hosted GitLab instance.
+const gitLabBaseURL = "https://gitlab.com"
+
+// gitLabLinkNextPattern extracts the "next" URL from a GitLab RFC 5988 Link
+// header, e.g. `<https://gitlab.com/api/v4/...&page=2>; rel="next"`.
+var gitLabLinkNextPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// gitLabSource lists the repositories (projects) belonging to a GitLab user
+// or group.
+type gitLabSource struct {
+	config SourceConfig
+}
+
+func newGitLabSource(config SourceConfig) *gitLabSource {
+	return &gitLabSource{config: config}
+}
+
+// ListRepositories finds all the projects belonging to the configured GitLab
+// user or group, following pagination until every page has been retrieved.
+func (s *gitLabSource) ListRepositories(ctx context.Context) ([]Repository, error) {
+	target := s.config
+
+	baseURL := target.BaseURL
+	if baseURL == "" {
+		baseURL = gitLabBaseURL
+	}
+
+	var resource string
+	switch target.Type {
+	case "group":
+		resource = "groups"
+	default:
+		resource = "users"
+	}
+	requestURL := fmt.Sprintf("%s/api/v4/%s/%s/projects?per_page=100", baseURL, resource, target.Entity)
+
+	var repoList []Repository
+	for requestURL != "" {
+		dat, nextURL, err := s.fetchPage(ctx, requestURL)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, repo := range dat {
+			repoName, _ := repo["path"].(string)
+			cloneURL, _ := repo["http_url_to_repo"].(string)
+			owner := target.Entity
+			if namespace, ok := repo["namespace"].(map[string]interface{}); ok {
+				if path, ok := namespace["path"].(string); ok {
+					owner = path
+				}
+			}
+			repoList = append(repoList, Repository{
+				name:     repoName,
+				cloneURL: cloneURL,
+				hoster:   "gitlab",
+				owner:    owner,
+			})
+		}
+
+		requestURL = nextURL
+	}
+
+	return repoList, nil
+}
+
+// fetchPage retrieves a single page of the project listing and returns the
+// URL of the next page, if any, parsed from the response's Link header.
+func (s *gitLabSource) fetchPage(ctx context.Context, requestURL string) ([]map[string]interface{}, string, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to build request to retrieve the list of repositories: %s", err)
+	}
+	request.Header.Set("PRIVATE-TOKEN", s.config.Token)
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to connect with the source to retrieve the list of repositories: %s", err)
+	}
+	defer response.Body.Close()
+	contents, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("Failed to retrieve the list of repositories: %s", err)
+	}
+
+	var dat []map[string]interface{}
+	if err := json.Unmarshal(contents, &dat); err != nil {
+		return nil, "", fmt.Errorf("Failed to parse JSON: %s", err)
+	}
+
+	var nextURL string
+	if matches := gitLabLinkNextPattern.FindStringSubmatch(response.Header.Get("Link")); len(matches) == 2 {
+		nextURL = matches[1]
+	}
+
+	return dat, nextURL, nil
+}
@@ -0,0 +1,12 @@
+package gitbackup
+
+// gogsSource lists the repositories belonging to a Gogs user or
+// organization. Gogs exposes an API compatible with Gitea's, so this simply
+// wraps giteaSource.
+type gogsSource struct {
+	*giteaSource
+}
+
+func newGogsSource(config SourceConfig) *gogsSource {
+	return &gogsSource{giteaSource: newGiteaSource(config)}
+}
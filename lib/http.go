@@ -0,0 +1,15 @@
+package gitbackup
+
+import (
+	"net/http"
+	"time"
+)
+
+// httpClientTimeout bounds every request made to list repositories from a
+// source, so a hung connection cannot stall a backup run indefinitely.
+const httpClientTimeout = 30 * time.Second
+
+// httpClient is shared by every Source implementation. Requests are built
+// with a context (see http.NewRequestWithContext) so callers can cancel a
+// listing in flight in addition to the timeout below.
+var httpClient = &http.Client{Timeout: httpClientTimeout}
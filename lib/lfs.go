@@ -0,0 +1,30 @@
+package gitbackup
+
+import (
+	"fmt"
+	"os/exec"
+)
+
+// lfsAvailable reports whether the git-lfs binary can be found on PATH.
+func lfsAvailable() bool {
+	_, err := exec.LookPath("git-lfs")
+	return err == nil
+}
+
+// lfsFetchAll pulls every LFS object for every ref into the mirror at dir.
+func lfsFetchAll(dir string, credential gitCredential) error {
+	out, err := runGit(dir, credential, "lfs", "fetch", "--all")
+	if err != nil {
+		return fmt.Errorf("Error fetching LFS objects: %s: %s", err, out)
+	}
+	return nil
+}
+
+// lfsPushAll pushes every LFS object referenced by dir's mirror to destURL.
+func lfsPushAll(dir string, destURL string, credential gitCredential) error {
+	out, err := runGit(dir, credential, "lfs", "push", "--all", destURL)
+	if err != nil {
+		return fmt.Errorf("Error pushing LFS objects to %s: %s: %s", destURL, err, out)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package gitbackup
+
+import "sync"
+
+// repoLocks guards concurrent access to each repository's local mirror
+// directory, so an in-progress fetch triggered by BackupTarget never races
+// with an archive or smart-HTTP request served by Serve's HTTP server.
+var repoLocks sync.Map // map[string]*sync.RWMutex
+
+// repoLock returns the lock guarding dir, creating it on first use.
+func repoLock(dir string) *sync.RWMutex {
+	actual, _ := repoLocks.LoadOrStore(dir, &sync.RWMutex{})
+	return actual.(*sync.RWMutex)
+}
@@ -0,0 +1,78 @@
+package gitbackup
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metrics accumulates the Prometheus gauges exposed at /metrics, updated as
+// BackupTarget reports progress Events.
+type metrics struct {
+	mu               sync.Mutex
+	lastSuccessUnix  map[string]int64
+	fetchDurationSec map[string]float64
+	reposSeen        map[string]map[string]struct{}
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		lastSuccessUnix:  make(map[string]int64),
+		fetchDurationSec: make(map[string]float64),
+		reposSeen:        make(map[string]map[string]struct{}),
+	}
+}
+
+// observe folds a single progress Event into the accumulated metrics.
+func (m *metrics) observe(event Event) {
+	if event.Type != EventSucceeded {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastSuccessUnix[event.Target] = time.Now().Unix()
+	m.fetchDurationSec[event.Target] = event.Duration.Seconds()
+
+	// Record the repo by name rather than incrementing a counter, so a repo
+	// backed up again on a later poll cycle doesn't inflate the total - it
+	// reports the number of repos under the target, not the number of
+	// successful fetches.
+	repos := m.reposSeen[event.Target]
+	if repos == nil {
+		repos = make(map[string]struct{})
+		m.reposSeen[event.Target] = repos
+	}
+	repos[event.Repo] = struct{}{}
+}
+
+// render formats the accumulated metrics in Prometheus text exposition
+// format.
+func (m *metrics) render() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var out strings.Builder
+
+	fmt.Fprintln(&out, "# HELP gitbackup_last_success_timestamp Unix timestamp of the last successful backup for a target.")
+	fmt.Fprintln(&out, "# TYPE gitbackup_last_success_timestamp gauge")
+	for target, ts := range m.lastSuccessUnix {
+		fmt.Fprintf(&out, "gitbackup_last_success_timestamp{target=%q} %d\n", target, ts)
+	}
+
+	fmt.Fprintln(&out, "# HELP gitbackup_fetch_duration_seconds Duration of the most recent clone or fetch for a target.")
+	fmt.Fprintln(&out, "# TYPE gitbackup_fetch_duration_seconds gauge")
+	for target, seconds := range m.fetchDurationSec {
+		fmt.Fprintf(&out, "gitbackup_fetch_duration_seconds{target=%q} %f\n", target, seconds)
+	}
+
+	fmt.Fprintln(&out, "# HELP gitbackup_repos_total Number of distinct repositories successfully backed up for a target.")
+	fmt.Fprintln(&out, "# TYPE gitbackup_repos_total gauge")
+	for target, repos := range m.reposSeen {
+		fmt.Fprintf(&out, "gitbackup_repos_total{target=%q} %d\n", target, len(repos))
+	}
+
+	return out.String()
+}
@@ -0,0 +1,40 @@
+package gitbackup
+
+import "time"
+
+// BackupOptions controls how BackupTarget backs up repositories: how many
+// run concurrently, how transient failures are retried, and how progress is
+// reported.
+type BackupOptions struct {
+	// Concurrency is how many repositories are backed up at once. Defaults
+	// to 1 when zero or negative.
+	Concurrency int
+	// RetryCount is how many additional attempts are made after a
+	// repository fails to clone or fetch.
+	RetryCount int
+	// RetryBackoff is the base delay between retries; it doubles after each
+	// subsequent attempt. Defaults to one second when zero or negative.
+	RetryBackoff time.Duration
+	// Events, when non-nil, receives a progress Event for every repository
+	// as it moves through the backup process. Sending blocks, so callers
+	// should either buffer the channel or drain it from another goroutine.
+	Events chan Event
+	// LFS, when true, also fetches every Git LFS object after a clone or
+	// fetch, and pushes them on to any configured destination. Ignored with
+	// a warning if the git-lfs binary isn't available.
+	LFS bool
+	// Structured, when true, lays repositories out as
+	// "<backupDirectory>/<hoster>/<owner>/<repo>.git" instead of the legacy
+	// "<backupDirectory>/<target>/<repo>".
+	Structured bool
+	// KeepN, when greater than zero, clones into a fresh, timestamped
+	// sibling directory on every run instead of fetching updates in place,
+	// then prunes all but the most recent KeepN snapshots.
+	KeepN int
+	// Zip, when true, archives each finished snapshot into a
+	// "<repo>-<timestamp>.tar.gz" and removes the working tree, so backups
+	// don't accumulate as loose mirrors on disk. Requires KeepN > 0: without
+	// timestamped snapshots there is nothing to archive but the one live
+	// mirror, and zipping that would remove the only copy on disk.
+	Zip bool
+}
@@ -0,0 +1,214 @@
+package gitbackup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// repositoryPath returns the base path a repository is backed up to, before
+// any snapshot timestamp is appended. With Structured set it groups
+// repositories by hoster and owner; otherwise it falls back to the legacy
+// layout keyed by the configured target name.
+func repositoryPath(target SourceConfig, repo Repository, backupDirectory string, structured bool) string {
+	if !structured {
+		return filepath.Join(backupDirectory, target.Name, repo.name)
+	}
+
+	hoster := repo.hoster
+	if hoster == "" {
+		hoster = target.Provider
+	}
+	owner := repo.owner
+	if owner == "" {
+		owner = target.Entity
+	}
+	return filepath.Join(backupDirectory, hoster, owner, repo.name+".git")
+}
+
+// snapshotSuffixPattern matches the "-<unix timestamp>" suffix appended to a
+// snapshot directory, and its optional ".tar.gz" extension once archived.
+var snapshotSuffixPattern = regexp.MustCompile(`-(\d+)(\.tar\.gz)?$`)
+
+// pruneSnapshots removes every sibling of basePath whose name matches
+// "<basePath>-<timestamp>[.tar.gz]" except for the keepN most recent ones.
+func pruneSnapshots(basePath string, keepN int) error {
+	dir := filepath.Dir(basePath)
+	prefix := filepath.Base(basePath) + "-"
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("Failed to list snapshots: %s", err)
+	}
+
+	type snapshot struct {
+		path      string
+		timestamp int64
+	}
+	var snapshots []snapshot
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		matches := snapshotSuffixPattern.FindStringSubmatch(name)
+		if matches == nil {
+			continue
+		}
+		timestamp, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, snapshot{path: filepath.Join(dir, name), timestamp: timestamp})
+	}
+
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].timestamp > snapshots[j].timestamp })
+
+	if keepN >= len(snapshots) {
+		return nil
+	}
+	for _, s := range snapshots[keepN:] {
+		if err := os.RemoveAll(s.path); err != nil {
+			return fmt.Errorf("Failed to prune old snapshot %s: %s", s.path, err)
+		}
+	}
+
+	return nil
+}
+
+// archiveSnapshot tars and gzips dir into "<dir>.tar.gz", removes the
+// working tree, and returns the archive's path.
+func archiveSnapshot(dir string) (string, error) {
+	archivePath := dir + ".tar.gz"
+
+	if err := writeTarGz(archivePath, dir); err != nil {
+		os.Remove(archivePath)
+		return "", fmt.Errorf("Failed to archive snapshot: %s", err)
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return "", fmt.Errorf("Failed to remove working tree after archiving: %s", err)
+	}
+
+	return archivePath, nil
+}
+
+func writeTarGz(archivePath string, dir string) error {
+	out, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if relPath == "." {
+			return nil
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = relPath
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(tw, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Restore unpacks a snapshot created with BackupOptions.Zip back into a
+// usable bare repository at target.
+func Restore(snapshot string, target string) error {
+	file, err := os.Open(snapshot)
+	if err != nil {
+		return fmt.Errorf("Failed to open snapshot: %s", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("Failed to read snapshot: %s", err)
+	}
+	defer gz.Close()
+
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return fmt.Errorf("Failed to create restore target: %s", err)
+	}
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to read snapshot: %s", err)
+		}
+
+		path := filepath.Join(target, header.Name)
+		if !strings.HasPrefix(path, filepath.Clean(target)+string(os.PathSeparator)) {
+			return fmt.Errorf("Failed to restore snapshot: entry %q escapes the restore target", header.Name)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, os.FileMode(header.Mode)); err != nil {
+				return fmt.Errorf("Failed to restore snapshot: %s", err)
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return fmt.Errorf("Failed to restore snapshot: %s", err)
+			}
+			out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode))
+			if err != nil {
+				return fmt.Errorf("Failed to restore snapshot: %s", err)
+			}
+			_, copyErr := io.Copy(out, tr)
+			out.Close()
+			if copyErr != nil {
+				return fmt.Errorf("Failed to restore snapshot: %s", copyErr)
+			}
+		}
+	}
+
+	return nil
+}
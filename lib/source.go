@@ -0,0 +1,45 @@
+package gitbackup
+
+import (
+	"context"
+	"fmt"
+)
+
+// Repository is a single git repository found on a source.
+type Repository struct {
+	name     string
+	cloneURL string
+	// hoster and owner identify where the repository lives, e.g.
+	// hoster="github", owner="golint-fixer". They are used to lay out
+	// backups when BackupOptions.Structured is set; a Source that leaves
+	// them blank falls back to the SourceConfig's Provider and Entity
+	// respectively (see repositoryPath).
+	hoster string
+	owner  string
+}
+
+// Source is the extension point for anything that can list the git
+// repositories belonging to an entity, e.g. a GitHub user or a GitLab group.
+// Adding a new provider means implementing this interface and registering it
+// in newSource below.
+type Source interface {
+	ListRepositories(ctx context.Context) ([]Repository, error)
+}
+
+// newSource builds the Source implementation for a SourceConfig's provider.
+func newSource(source SourceConfig) (Source, error) {
+	switch source.Provider {
+	case "github":
+		return newGitHubSource(source), nil
+	case "bitbucket":
+		return newBitBucketSource(source), nil
+	case "gitlab":
+		return newGitLabSource(source), nil
+	case "gitea":
+		return newGiteaSource(source), nil
+	case "gogs":
+		return newGogsSource(source), nil
+	default:
+		return nil, fmt.Errorf(`"%s" is not a recognized source provider`, source.Provider)
+	}
+}